@@ -0,0 +1,88 @@
+// Package credentials resolves the username and password used to authenticate with the captive portal from a
+// configurable, ordered chain of sources: the OS keyring, environment variables, a ~/.netrc-style file, and an
+// interactive prompt as the final fallback.
+package credentials
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Machine is the netrc/keyring host credentials are keyed on.
+const Machine = "gateway.iitk.ac.in"
+
+// Credentials holds a resolved username/password pair. Password is zeroed by Zero once it is no longer needed.
+type Credentials struct {
+	Username string
+	Password []byte
+}
+
+// Zero overwrites Password in place so the cleartext password doesn't linger in memory longer than necessary.
+func (c *Credentials) Zero() {
+	for i := range c.Password {
+		c.Password[i] = 0
+	}
+}
+
+// Provider resolves a Credentials from a single source.
+type Provider interface {
+	// Name identifies the provider for use with --credentials-source and in error messages.
+	Name() string
+	// Get returns the resolved credentials, or an error if this source has none available.
+	Get() (Credentials, error)
+}
+
+// Providers returns the named providers in order, suitable for passing to Resolve. Recognized names are "flags",
+// "keyring", "env", "netrc" and "prompt". A --username given without --password fails FlagProvider, but is still
+// carried through to seed PromptProvider's default, so --username alone behaves as "prompt for the password only"
+// instead of being silently dropped once the chain falls through to prompt.
+func Providers(names []string, flagUsername, flagPassword string) ([]Provider, error) {
+	providers := make([]Provider, 0, len(names))
+	for _, name := range names {
+		switch name {
+		case "flags":
+			providers = append(providers, FlagProvider{Username: flagUsername, Password: flagPassword})
+		case "keyring":
+			providers = append(providers, KeyringProvider{})
+		case "env":
+			providers = append(providers, EnvProvider{})
+		case "netrc":
+			providers = append(providers, NetrcProvider{})
+		case "prompt":
+			providers = append(providers, PromptProvider{DefaultUsername: flagUsername})
+		default:
+			return nil, fmt.Errorf("unknown credentials source: %v", name)
+		}
+	}
+	return providers, nil
+}
+
+// ParseSources splits a comma-separated --credentials-source value into provider names.
+func ParseSources(csv string) []string {
+	parts := strings.Split(csv, ",")
+	names := make([]string, 0, len(parts))
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if part != "" {
+			names = append(names, part)
+		}
+	}
+	return names
+}
+
+// Resolve tries each provider in order and returns the first successfully resolved Credentials. If every provider
+// fails, the error from the last one is returned.
+func Resolve(providers []Provider) (Credentials, error) {
+	var err error
+	for _, provider := range providers {
+		var creds Credentials
+		creds, err = provider.Get()
+		if err == nil {
+			return creds, nil
+		}
+	}
+	if err == nil {
+		err = fmt.Errorf("no credentials source configured")
+	}
+	return Credentials{}, err
+}
@@ -0,0 +1,20 @@
+package credentials
+
+import (
+	"fmt"
+	"os"
+)
+
+// EnvProvider resolves credentials from the FORTIAUTH_USERNAME and FORTIAUTH_PASSWORD environment variables.
+type EnvProvider struct{}
+
+func (EnvProvider) Name() string { return "env" }
+
+func (EnvProvider) Get() (Credentials, error) {
+	username := os.Getenv("FORTIAUTH_USERNAME")
+	password := os.Getenv("FORTIAUTH_PASSWORD")
+	if username == "" || password == "" {
+		return Credentials{}, fmt.Errorf("FORTIAUTH_USERNAME/FORTIAUTH_PASSWORD not set")
+	}
+	return Credentials{Username: username, Password: []byte(password)}, nil
+}
@@ -0,0 +1,18 @@
+package credentials
+
+import "fmt"
+
+// FlagProvider resolves credentials supplied directly via --username/--password.
+type FlagProvider struct {
+	Username string
+	Password string
+}
+
+func (FlagProvider) Name() string { return "flags" }
+
+func (p FlagProvider) Get() (Credentials, error) {
+	if p.Username == "" || p.Password == "" {
+		return Credentials{}, fmt.Errorf("--username/--password not set")
+	}
+	return Credentials{Username: p.Username, Password: []byte(p.Password)}, nil
+}
@@ -0,0 +1,31 @@
+package credentials
+
+import "github.com/zalando/go-keyring"
+
+// keyringService is the service name credentials are stored under in the OS keyring.
+const keyringService = "fortiauth"
+
+// KeyringProvider resolves credentials previously stored with StoreInKeyring via `fortiauth login`.
+type KeyringProvider struct{}
+
+func (KeyringProvider) Name() string { return "keyring" }
+
+func (KeyringProvider) Get() (Credentials, error) {
+	username, err := keyring.Get(keyringService, "username")
+	if err != nil {
+		return Credentials{}, err
+	}
+	password, err := keyring.Get(keyringService, "password")
+	if err != nil {
+		return Credentials{}, err
+	}
+	return Credentials{Username: username, Password: []byte(password)}, nil
+}
+
+// StoreInKeyring saves username and password in the OS keyring for later retrieval by KeyringProvider.
+func StoreInKeyring(username, password string) error {
+	if err := keyring.Set(keyringService, "username", username); err != nil {
+		return err
+	}
+	return keyring.Set(keyringService, "password", password)
+}
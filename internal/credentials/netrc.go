@@ -0,0 +1,65 @@
+package credentials
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// NetrcProvider resolves credentials from a ~/.netrc entry for Machine, e.g.:
+//
+//	machine gateway.iitk.ac.in
+//	login myusername
+//	password mypassword
+type NetrcProvider struct {
+	// Path overrides the default ~/.netrc location; used in tests.
+	Path string
+}
+
+func (NetrcProvider) Name() string { return "netrc" }
+
+func (p NetrcProvider) Get() (Credentials, error) {
+	path := p.Path
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return Credentials{}, err
+		}
+		path = filepath.Join(home, ".netrc")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	fields := strings.Fields(string(data))
+	var username, password string
+	inMachine := false
+	for i := 0; i < len(fields); i++ {
+		switch fields[i] {
+		case "machine":
+			if i+1 >= len(fields) {
+				continue
+			}
+			inMachine = fields[i+1] == Machine
+			i++
+		case "login":
+			if inMachine && i+1 < len(fields) {
+				username = fields[i+1]
+				i++
+			}
+		case "password":
+			if inMachine && i+1 < len(fields) {
+				password = fields[i+1]
+				i++
+			}
+		}
+	}
+
+	if username == "" || password == "" {
+		return Credentials{}, fmt.Errorf("no netrc entry found for machine %v", Machine)
+	}
+	return Credentials{Username: username, Password: []byte(password)}, nil
+}
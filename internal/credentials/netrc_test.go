@@ -0,0 +1,44 @@
+package credentials
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeNetrc(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), ".netrc")
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatalf("failed to write netrc fixture: %v", err)
+	}
+	return path
+}
+
+func TestNetrcProviderGet(t *testing.T) {
+	path := writeNetrc(t, "machine "+Machine+"\nlogin myusername\npassword mypassword\n")
+
+	creds, err := NetrcProvider{Path: path}.Get()
+	if err != nil {
+		t.Fatalf("Get() returned error: %v", err)
+	}
+	if creds.Username != "myusername" || string(creds.Password) != "mypassword" {
+		t.Fatalf("Get() = %+v, want username=myusername password=mypassword", creds)
+	}
+}
+
+func TestNetrcProviderGetIgnoresOtherMachines(t *testing.T) {
+	path := writeNetrc(t, "machine example.com\nlogin someoneelse\npassword notit\n")
+
+	if _, err := (NetrcProvider{Path: path}).Get(); err == nil {
+		t.Fatal("Get() succeeded, want error for a netrc with no entry for Machine")
+	}
+}
+
+func TestNetrcProviderGetMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "does-not-exist")
+
+	if _, err := (NetrcProvider{Path: path}).Get(); err == nil {
+		t.Fatal("Get() succeeded, want error for a missing netrc file")
+	}
+}
@@ -0,0 +1,46 @@
+package credentials
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// PromptProvider resolves credentials by asking the user interactively. It never fails, and exists as the last
+// resort in a provider chain.
+type PromptProvider struct {
+	// DefaultUsername, if set, is used when the user presses enter at the username prompt without typing anything.
+	// This lets a partial --username flag (no --password) seed the prompt instead of being silently ignored when
+	// FlagProvider falls through.
+	DefaultUsername string
+}
+
+func (PromptProvider) Name() string { return "prompt" }
+
+func (p PromptProvider) Get() (Credentials, error) {
+	if p.DefaultUsername != "" {
+		fmt.Printf("username [%s]: ", p.DefaultUsername)
+	} else {
+		fmt.Print("username: ")
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return Credentials{}, fmt.Errorf("failed to read username: %w", err)
+	}
+	username := strings.TrimSpace(line)
+	if username == "" {
+		username = p.DefaultUsername
+	}
+
+	fmt.Print("password: ")
+	password, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Println()
+	if err != nil {
+		return Credentials{}, fmt.Errorf("failed to read password: %w", err)
+	}
+
+	return Credentials{Username: username, Password: password}, nil
+}
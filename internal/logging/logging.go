@@ -0,0 +1,74 @@
+// Package logging builds the structured (slog) logger used throughout fortiauth, optionally writing to a rotating
+// log file via lumberjack instead of (or in addition to) stderr.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Options configures New.
+type Options struct {
+	// Format is "text" or "json".
+	Format string
+	// Level is "debug", "info", "warn" or "error".
+	Level string
+	// File is the path to a log file. If empty, logs are written to stderr.
+	File string
+	// MaxSizeMB is the maximum size in megabytes of a log file before it gets rotated.
+	MaxSizeMB int
+	// MaxBackups is the maximum number of rotated log files to keep.
+	MaxBackups int
+	// MaxAgeDays is the maximum number of days to retain rotated log files.
+	MaxAgeDays int
+}
+
+// New builds a *slog.Logger according to opts.
+func New(opts Options) (*slog.Logger, error) {
+	level, err := parseLevel(opts.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	var w io.Writer = os.Stderr
+	if opts.File != "" {
+		w = &lumberjack.Logger{
+			Filename:   opts.File,
+			MaxSize:    opts.MaxSizeMB,
+			MaxBackups: opts.MaxBackups,
+			MaxAge:     opts.MaxAgeDays,
+		}
+	}
+
+	handlerOpts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch opts.Format {
+	case "json":
+		handler = slog.NewJSONHandler(w, handlerOpts)
+	case "text":
+		handler = slog.NewTextHandler(w, handlerOpts)
+	default:
+		return nil, fmt.Errorf("unknown log format: %v", opts.Format)
+	}
+
+	return slog.New(handler), nil
+}
+
+func parseLevel(level string) (slog.Level, error) {
+	switch level {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return 0, fmt.Errorf("unknown log level: %v", level)
+	}
+}
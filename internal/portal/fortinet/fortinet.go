@@ -0,0 +1,176 @@
+// Package fortinet implements portal.Backend for FortiGate captive portals, which redirect an unauthenticated
+// client to an `fgtauth` page and, after a successful POST, to a `keepalive` page.
+package fortinet
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/samiksome92/fortiauth/internal/credentials"
+	"github.com/samiksome92/fortiauth/internal/portal"
+	"github.com/samiksome92/fortiauth/internal/retry"
+)
+
+func init() {
+	portal.Register("fortinet-iitk", func(client *retry.Client) portal.Backend { return newIITK(client) })
+	portal.Register("fortinet", func(client *retry.Client) portal.Backend { return newGeneric(client) })
+}
+
+// Backend implements portal.Backend for FortiGate portals. The IITK and generic flavors differ only in the
+// regexes used to recognize the portal's pages.
+type Backend struct {
+	client *retry.Client
+
+	checkRegex     *regexp.Regexp // matches the check-page redirect to the fgtauth URL; group 1 is that URL.
+	authRegex      *regexp.Regexp // matches the auth page's hidden "magic" field; group 1 is its value.
+	keepaliveRegex *regexp.Regexp // matches the post-login redirect to the keepalive URL; group 1 is that URL.
+
+	authURL string // set by Detect, consumed by Login.
+}
+
+// newIITK returns a Backend hard-coded to IIT Kanpur's gateway.iitk.ac.in deployment, matching the tool's original,
+// pre-pluggable-backend behavior.
+func newIITK(client *retry.Client) *Backend {
+	return &Backend{
+		client:         client,
+		checkRegex:     regexp.MustCompile(`^<html><body><script language="JavaScript">window\.location="(https:\/\/gateway\.iitk\.ac\.in:\d+\/fgtauth\?[a-f\d]+)";<\/script><\/body><\/html>$`),
+		authRegex:      regexp.MustCompile(`<input type="hidden" name="magic" value="([a-f\d]+)">`),
+		keepaliveRegex: regexp.MustCompile(`<html><body><script language="JavaScript">window\.location="(https:\/\/gateway\.iitk\.ac\.in:\d+\/keepalive\?[a-f\d]+)";<\/script><\/body><\/html>`),
+	}
+}
+
+// newGeneric returns a Backend that recognizes any FortiGate deployment's fgtauth/keepalive redirect, not just
+// gateway.iitk.ac.in, so the same binary works at other campuses/offices that also deploy FortiGate.
+func newGeneric(client *retry.Client) *Backend {
+	return &Backend{
+		client:         client,
+		checkRegex:     regexp.MustCompile(`window\.location="(https?:\/\/[^"]+\/fgtauth\?[a-f\d]+)"`),
+		authRegex:      regexp.MustCompile(`<input type="hidden" name="magic" value="([a-f\d]+)">`),
+		keepaliveRegex: regexp.MustCompile(`window\.location="(https?:\/\/[^"]+\/keepalive\?[a-f\d]+)"`),
+	}
+}
+
+// SetAuthRegex overrides the pattern used to extract the auth page's magic value. Group 1 must be the value.
+func (b *Backend) SetAuthRegex(pattern string) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return err
+	}
+	b.authRegex = re
+	return nil
+}
+
+// SetKeepaliveRegex overrides the pattern used to recognize the post-login redirect to the keepalive URL. Group 1
+// must be that URL.
+func (b *Backend) SetKeepaliveRegex(pattern string) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return err
+	}
+	b.keepaliveRegex = re
+	return nil
+}
+
+// Detect checks whether the user is logged in.
+//
+// There's no good way to know if the regex is unmatched because Fortinet changed the template or because we
+// actually got the requested webpage. For now, we assume that if the regex does not match the user is logged in.
+func (b *Backend) Detect(ctx context.Context, checkURL string) (portal.State, error) {
+	resp, err := b.client.Get(ctx, checkURL)
+	if err != nil {
+		return portal.State{}, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return portal.State{}, err
+	}
+	if len(body) == 0 {
+		return portal.State{}, fmt.Errorf("empty response body")
+	}
+
+	match := b.checkRegex.FindSubmatch(body)
+	if match == nil {
+		return portal.State{LoggedIn: true}, nil
+	}
+
+	b.authURL = string(match[1])
+	return portal.State{LoggedIn: false}, nil
+}
+
+// Login authenticates with the credentials against the auth URL discovered by the most recent Detect call.
+func (b *Backend) Login(ctx context.Context, creds credentials.Credentials) (portal.Session, error) {
+	resp, err := b.client.Get(ctx, b.authURL)
+	if err != nil {
+		return portal.Session{}, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return portal.Session{}, err
+	}
+
+	match := b.authRegex.FindSubmatch(body)
+	if match == nil {
+		return portal.Session{}, fmt.Errorf("magic value not found")
+	}
+
+	values := url.Values{}
+	values.Set("username", creds.Username)
+	values.Set("password", string(creds.Password))
+	values.Set("magic", string(match[1]))
+	resp, err = b.client.PostForm(ctx, b.authURL[:8+strings.Index(b.authURL[8:], "/")], values)
+	if err != nil {
+		return portal.Session{}, err
+	}
+	defer resp.Body.Close()
+
+	body, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return portal.Session{}, err
+	}
+
+	// Probably should relax the regex matching a bit here and just look for keepalive url.
+	match = b.keepaliveRegex.FindSubmatch(body)
+	if match == nil {
+		return portal.Session{}, fmt.Errorf("keepalive url not found")
+	}
+
+	return portal.Session{KeepaliveURL: string(match[1])}, nil
+}
+
+// Keepalive sends a keepalive request.
+func (b *Backend) Keepalive(ctx context.Context, session portal.Session) error {
+	resp, err := b.client.Get(ctx, session.KeepaliveURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("response status: %v", resp.Status)
+	}
+
+	return nil
+}
+
+// Logout ends session. FortiGate portals use the same URL for keepalive and logout, just with the path swapped.
+func (b *Backend) Logout(ctx context.Context, session portal.Session) error {
+	resp, err := b.client.Get(ctx, strings.Replace(session.KeepaliveURL, "keepalive", "logout", 1))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("response status: %v", resp.Status)
+	}
+
+	return nil
+}
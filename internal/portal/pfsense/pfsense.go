@@ -0,0 +1,41 @@
+// Package pfsense is a stub portal.Backend for pfSense captive portals. pfSense's portal flow (a POST to
+// `index.php?zone=...` with a `auth_voucher`/`auth_user`/`auth_pass` form and a logout popup window) differs enough
+// from FortiGate's that it isn't implemented yet; this stub exists so --portal=pfsense fails with a clear error
+// rather than an unknown-backend one.
+package pfsense
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/samiksome92/fortiauth/internal/credentials"
+	"github.com/samiksome92/fortiauth/internal/portal"
+	"github.com/samiksome92/fortiauth/internal/retry"
+)
+
+func init() {
+	portal.Register("pfsense", func(client *retry.Client) portal.Backend { return &Backend{client: client} })
+}
+
+// Backend is an unimplemented stub for pfSense captive portals.
+type Backend struct {
+	client *retry.Client
+}
+
+var errNotImplemented = fmt.Errorf("pfsense backend is not implemented yet")
+
+func (b *Backend) Detect(ctx context.Context, checkURL string) (portal.State, error) {
+	return portal.State{}, errNotImplemented
+}
+
+func (b *Backend) Login(ctx context.Context, creds credentials.Credentials) (portal.Session, error) {
+	return portal.Session{}, errNotImplemented
+}
+
+func (b *Backend) Keepalive(ctx context.Context, session portal.Session) error {
+	return errNotImplemented
+}
+
+func (b *Backend) Logout(ctx context.Context, session portal.Session) error {
+	return errNotImplemented
+}
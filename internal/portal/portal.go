@@ -0,0 +1,78 @@
+// Package portal defines the interface fortiauth uses to talk to a captive portal, so that support for portal
+// vendors/deployments beyond IIT Kanpur's Fortinet setup can be added without touching the main loop. Backend
+// implementations register themselves via Register, typically from an init function, and are looked up by name
+// with New.
+package portal
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/samiksome92/fortiauth/internal/credentials"
+	"github.com/samiksome92/fortiauth/internal/retry"
+)
+
+// State describes whether the user is currently logged in to the portal.
+type State struct {
+	LoggedIn bool
+}
+
+// Session identifies an authenticated session with the portal.
+type Session struct {
+	KeepaliveURL string
+}
+
+// Backend talks to a specific captive portal vendor/deployment. A Backend instance is stateful: Detect records
+// whatever it needs (e.g. an authentication URL) to make the following Login call succeed.
+type Backend interface {
+	// Detect checks checkURL and reports whether the user is already logged in.
+	Detect(ctx context.Context, checkURL string) (State, error)
+	// Login authenticates with the credentials discovered by the most recent Detect call.
+	Login(ctx context.Context, creds credentials.Credentials) (Session, error)
+	// Keepalive sends a keepalive request for session.
+	Keepalive(ctx context.Context, session Session) error
+	// Logout ends session.
+	Logout(ctx context.Context, session Session) error
+}
+
+// Factory builds a Backend bound to client.
+type Factory func(client *retry.Client) Backend
+
+var registry = map[string]Factory{}
+
+// Register adds a named backend factory. It is meant to be called from the init function of a backend package.
+// Register panics if name is already registered, since that indicates two backend packages collided on a name.
+func Register(name string, factory Factory) {
+	if _, ok := registry[name]; ok {
+		panic(fmt.Sprintf("portal: backend %q registered twice", name))
+	}
+	registry[name] = factory
+}
+
+// New builds the named backend bound to client. The caller must blank-import the backend's package first so its
+// init function runs and registers it.
+func New(name string, client *retry.Client) (Backend, error) {
+	factory, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown portal backend %q (available: %v)", name, Names())
+	}
+	return factory(client), nil
+}
+
+// Names returns the sorted names of all registered backends.
+func Names() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RegexOverrider is implemented by backends that support overriding their detection patterns via --auth-regex and
+// --keepalive-regex, without needing to recompile when a vendor tweaks its page template.
+type RegexOverrider interface {
+	SetAuthRegex(pattern string) error
+	SetKeepaliveRegex(pattern string) error
+}
@@ -0,0 +1,194 @@
+// Package retry provides an http.Client wrapper that retries transient failures with exponential backoff and
+// jitter, loosely modeled after hashicorp/go-retryablehttp.
+package retry
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// CheckRetryFunc decides whether a request should be retried given the response and/or error from the previous
+// attempt. Returning a non-nil error aborts the retry loop immediately with that error.
+type CheckRetryFunc func(resp *http.Response, err error) (bool, error)
+
+// BackoffFunc computes how long to wait before the next attempt, given the configured base and cap durations, the
+// jitter fraction and the zero-indexed attempt number.
+type BackoffFunc func(base, cap time.Duration, jitter float64, attempt int) time.Duration
+
+// Client wraps an *http.Client, retrying requests that fail with a transient error according to CheckRetry, waiting
+// Backoff between attempts.
+type Client struct {
+	HTTPClient *http.Client
+
+	// Jar is the cookie jar backing HTTPClient. It's kept as its own field (rather than just reaching into
+	// HTTPClient.Jar) so callers that need to persist/restore cookies, such as internal/sessionstore, can do so
+	// without type-asserting http.CookieJar back to a *cookiejar.Jar.
+	Jar *cookiejar.Jar
+
+	// RetryMax is the maximum number of retries after the initial attempt.
+	RetryMax int
+	// RetryWaitBase is the base delay used to compute the backoff for the first retry.
+	RetryWaitBase time.Duration
+	// RetryWaitCap is the maximum delay between retries, regardless of attempt number.
+	RetryWaitCap time.Duration
+	// Jitter is the fraction of the computed backoff (0-1) added as random jitter.
+	Jitter float64
+
+	CheckRetry CheckRetryFunc
+	Backoff    BackoffFunc
+
+	// Logger, if set, receives a debug record for every attempt (with "attempt", "url" and "latency_ms" fields) and a
+	// warn record when the retry budget is exhausted.
+	Logger *slog.Logger
+}
+
+// NewClient returns a Client configured with the given parameters, DefaultCheckRetry and DefaultBackoff. A fresh,
+// empty cookie jar is attached so that captive portals which rely on session cookies work out of the box.
+func NewClient(retryMax int, retryWaitBase, retryWaitCap time.Duration, jitter float64) *Client {
+	jar, _ := cookiejar.New(nil)
+	return &Client{
+		HTTPClient:    &http.Client{Jar: jar},
+		Jar:           jar,
+		RetryMax:      retryMax,
+		RetryWaitBase: retryWaitBase,
+		RetryWaitCap:  retryWaitCap,
+		Jitter:        jitter,
+		CheckRetry:    DefaultCheckRetry,
+		Backoff:       DefaultBackoff,
+	}
+}
+
+// DefaultBackoff computes an exponential backoff (base * 2^attempt, capped at cap) plus up to jitter*backoff of
+// random jitter.
+func DefaultBackoff(base, cap time.Duration, jitter float64, attempt int) time.Duration {
+	wait := float64(base) * math.Pow(2, float64(attempt))
+	if wait <= 0 || wait > float64(cap) {
+		wait = float64(cap)
+	}
+	if jitter > 0 {
+		wait += rand.Float64() * jitter * wait
+	}
+	return time.Duration(wait)
+}
+
+// DefaultCheckRetry retries on network-level errors (DNS failures, connection refused/reset, TLS errors,
+// too-many-redirect loops such as a captive portal bouncing between auth pages) and on 5xx responses. Errors that
+// retrying can't fix (an unsupported/missing URL scheme, a canceled or timed-out context) are surfaced immediately
+// instead of burning the retry budget. 4xx responses and successful requests are not retried since they are
+// considered a definitive answer from the server.
+func DefaultCheckRetry(resp *http.Response, err error) (bool, error) {
+	if err != nil {
+		var urlErr *url.Error
+		if !errors.As(err, &urlErr) {
+			return false, nil
+		}
+
+		switch {
+		case strings.Contains(urlErr.Err.Error(), "stopped after"):
+			// Redirect loops (e.g. captive-portal pages bouncing between each other).
+			return true, nil
+		case errors.Is(urlErr.Err, context.Canceled), errors.Is(urlErr.Err, context.DeadlineExceeded):
+			// The caller gave up; don't retry on top of that.
+			return false, urlErr.Err
+		case strings.Contains(urlErr.Err.Error(), "unsupported protocol scheme"),
+			strings.Contains(urlErr.Err.Error(), "missing protocol scheme"):
+			// A malformed request URL; retrying can't fix it.
+			return false, nil
+		}
+
+		var dnsErr *net.DNSError
+		var opErr *net.OpError
+		var tlsErr *tls.CertificateVerificationError
+		if errors.As(urlErr.Err, &dnsErr) || errors.As(urlErr.Err, &opErr) || errors.As(urlErr.Err, &tlsErr) {
+			return true, nil
+		}
+		return false, nil
+	}
+
+	if resp != nil && resp.StatusCode >= 500 {
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// Do sends req, retrying according to c.CheckRetry and c.Backoff until a non-retryable outcome is reached or
+// c.RetryMax attempts have been made. req.GetBody (automatically set by http.NewRequest for common body types) is
+// used to rebuild the request body between attempts.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	var resp *http.Response
+	var err error
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, gbErr := req.GetBody()
+			if gbErr != nil {
+				return nil, gbErr
+			}
+			req.Body = body
+		}
+
+		start := time.Now()
+		resp, err = c.HTTPClient.Do(req)
+		latency := time.Since(start)
+
+		if c.Logger != nil {
+			c.Logger.Debug("retry attempt", "attempt", attempt, "url", req.URL.String(), "latency_ms", latency.Milliseconds())
+		}
+
+		retry, checkErr := c.CheckRetry(resp, err)
+		if checkErr != nil {
+			return resp, checkErr
+		}
+		if !retry {
+			return resp, err
+		}
+		if attempt >= c.RetryMax {
+			if c.Logger != nil {
+				c.Logger.Warn("retry budget exhausted", "attempt", attempt, "url", req.URL.String())
+			}
+			if resp != nil {
+				resp.Body.Close()
+			}
+			if err != nil {
+				return resp, fmt.Errorf("giving up after %d attempt(s): %w", attempt+1, err)
+			}
+			return resp, fmt.Errorf("giving up after %d attempt(s): response status %v", attempt+1, resp.Status)
+		}
+
+		if resp != nil {
+			resp.Body.Close()
+		}
+		time.Sleep(c.Backoff(c.RetryWaitBase, c.RetryWaitCap, c.Jitter, attempt))
+	}
+}
+
+// Get is a convenience wrapper around Do for GET requests, analogous to http.Get.
+func (c *Client) Get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(req)
+}
+
+// PostForm is a convenience wrapper around Do for form-encoded POST requests, analogous to http.PostForm.
+func (c *Client) PostForm(ctx context.Context, postURL string, data url.Values) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, postURL, strings.NewReader(data.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return c.Do(req)
+}
@@ -0,0 +1,100 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestDefaultCheckRetry(t *testing.T) {
+	tests := []struct {
+		name      string
+		resp      *http.Response
+		err       error
+		wantRetry bool
+		wantErr   bool
+	}{
+		{"success", &http.Response{StatusCode: 200}, nil, false, false},
+		{"4xx is not retried", &http.Response{StatusCode: 404}, nil, false, false},
+		{"5xx is retried", &http.Response{StatusCode: 503}, nil, true, false},
+		{
+			"dns error is retried",
+			nil,
+			&url.Error{Op: "Get", URL: "http://x", Err: &net.DNSError{Err: "no such host", Name: "x"}},
+			true, false,
+		},
+		{
+			"connection refused is retried",
+			nil,
+			&url.Error{Op: "Get", URL: "http://x", Err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}},
+			true, false,
+		},
+		{
+			"redirect loop is retried",
+			nil,
+			&url.Error{Op: "Get", URL: "http://x", Err: errors.New("stopped after 10 redirects")},
+			true, false,
+		},
+		{
+			"unsupported protocol scheme is not retried",
+			nil,
+			&url.Error{Op: "Get", URL: "ftp://x", Err: errors.New("unsupported protocol scheme \"ftp\"")},
+			false, false,
+		},
+		{
+			"canceled context is not retried and surfaces the error",
+			nil,
+			&url.Error{Op: "Get", URL: "http://x", Err: context.Canceled},
+			false, true,
+		},
+		{
+			"non-url error is not retried",
+			nil,
+			errors.New("boom"),
+			false, false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			retry, err := DefaultCheckRetry(tt.resp, tt.err)
+			if retry != tt.wantRetry {
+				t.Errorf("DefaultCheckRetry() retry = %v, want %v", retry, tt.wantRetry)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DefaultCheckRetry() err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDefaultBackoffGrowsAndCaps(t *testing.T) {
+	base := 100 * time.Millisecond
+	cap := time.Second
+
+	if got := DefaultBackoff(base, cap, 0, 0); got != base {
+		t.Errorf("DefaultBackoff(attempt=0) = %v, want %v", got, base)
+	}
+	if got := DefaultBackoff(base, cap, 0, 2); got != 4*base {
+		t.Errorf("DefaultBackoff(attempt=2) = %v, want %v", got, 4*base)
+	}
+	if got := DefaultBackoff(base, cap, 0, 10); got != cap {
+		t.Errorf("DefaultBackoff(attempt=10) = %v, want capped at %v", got, cap)
+	}
+}
+
+func TestDefaultBackoffJitterStaysWithinBounds(t *testing.T) {
+	base := 100 * time.Millisecond
+	cap := time.Second
+
+	for i := 0; i < 20; i++ {
+		got := DefaultBackoff(base, cap, 0.2, 1)
+		if got < 2*base || got > cap {
+			t.Fatalf("DefaultBackoff() = %v, want within [%v, %v]", got, 2*base, cap)
+		}
+	}
+}
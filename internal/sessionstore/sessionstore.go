@@ -0,0 +1,109 @@
+// Package sessionstore persists a fortiauth session (login state, keepalive URL and the portal's session cookies) to
+// disk, so a restarted process can resume keepaliving instead of running through check/auth again.
+package sessionstore
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/samiksome92/fortiauth/internal/retry"
+)
+
+// File is the on-disk representation of a session.
+type File struct {
+	LoggedIn     bool           `json:"logged_in"`
+	KeepaliveURL string         `json:"keepalive_url"`
+	Cookies      []*http.Cookie `json:"cookies"`
+}
+
+// DefaultPath returns $XDG_STATE_HOME/fortiauth/session.json, falling back to $HOME/.local/state/fortiauth/session.json
+// if XDG_STATE_HOME is unset, per the XDG Base Directory spec.
+func DefaultPath() string {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "fortiauth", "session.json")
+}
+
+// BuildFile captures the current session state and, if keepaliveURL is set, the cookies client's jar holds for that
+// URL's origin.
+func BuildFile(loggedIn bool, keepaliveURL string, client *retry.Client) (File, error) {
+	f := File{LoggedIn: loggedIn, KeepaliveURL: keepaliveURL}
+	if keepaliveURL == "" {
+		return f, nil
+	}
+
+	u, err := url.Parse(keepaliveURL)
+	if err != nil {
+		return File{}, fmt.Errorf("parsing keepalive url: %w", err)
+	}
+	f.Cookies = client.Jar.Cookies(u)
+	return f, nil
+}
+
+// Restore loads the file's cookies into client's jar so requests against the keepalive URL carry the saved session.
+func (f File) Restore(client *retry.Client) error {
+	if f.KeepaliveURL == "" || len(f.Cookies) == 0 {
+		return nil
+	}
+
+	u, err := url.Parse(f.KeepaliveURL)
+	if err != nil {
+		return fmt.Errorf("parsing keepalive url: %w", err)
+	}
+	client.Jar.SetCookies(u, f.Cookies)
+	return nil
+}
+
+// Save writes f to path atomically (write to a temp file in the same directory, then rename over path).
+func Save(path string, f File) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".session-*.json.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Load reads and parses the file at path.
+func Load(path string) (File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return File{}, err
+	}
+
+	var f File
+	if err := json.Unmarshal(data, &f); err != nil {
+		return File{}, fmt.Errorf("parsing session file: %w", err)
+	}
+	return f, nil
+}
@@ -0,0 +1,64 @@
+package sessionstore
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/samiksome92/fortiauth/internal/retry"
+)
+
+func TestSaveLoadRoundTrip(t *testing.T) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatalf("cookiejar.New() error: %v", err)
+	}
+	client := &retry.Client{Jar: jar}
+
+	const keepaliveURL = "http://gateway.iitk.ac.in/keepalive"
+	u, _ := url.Parse(keepaliveURL)
+	jar.SetCookies(u, []*http.Cookie{{Name: "session", Value: "abc123"}})
+
+	f, err := BuildFile(true, keepaliveURL, client)
+	if err != nil {
+		t.Fatalf("BuildFile() error: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "session.json")
+	if err := Save(path, f); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if loaded.LoggedIn != true || loaded.KeepaliveURL != keepaliveURL {
+		t.Fatalf("Load() = %+v, want LoggedIn=true KeepaliveURL=%v", loaded, keepaliveURL)
+	}
+	if len(loaded.Cookies) != 1 || loaded.Cookies[0].Name != "session" || loaded.Cookies[0].Value != "abc123" {
+		t.Fatalf("Load() cookies = %+v, want a single session=abc123 cookie", loaded.Cookies)
+	}
+
+	restoreJar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatalf("cookiejar.New() error: %v", err)
+	}
+	restoreClient := &retry.Client{Jar: restoreJar}
+	if err := loaded.Restore(restoreClient); err != nil {
+		t.Fatalf("Restore() error: %v", err)
+	}
+	cookies := restoreJar.Cookies(u)
+	if len(cookies) != 1 || cookies[0].Name != "session" || cookies[0].Value != "abc123" {
+		t.Fatalf("Restore() left cookies %+v, want a single session=abc123 cookie", cookies)
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "does-not-exist.json")
+	if _, err := Load(path); err == nil {
+		t.Fatal("Load() succeeded, want error for a missing file")
+	}
+}
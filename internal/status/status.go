@@ -0,0 +1,88 @@
+// Package status exposes fortiauth's login state over a local HTTP server, for wiring into systemd watchdogs, tray
+// apps or Prometheus without scraping stdout logs.
+package status
+
+import (
+	"fmt"
+	"net/http"
+	"sync/atomic"
+)
+
+// Metrics holds the counters/gauges exposed by Server. All fields are safe for concurrent use and are meant to be
+// updated from the main loop as it drives the portal.Backend.
+type Metrics struct {
+	AuthAttempts      atomic.Uint64
+	KeepaliveFailures atomic.Uint64
+	LoggedIn          atomic.Bool
+	LastKeepaliveUnix atomic.Int64
+}
+
+// Server serves /healthz, /metrics and /logout on a single http.Server.
+type Server struct {
+	httpServer *http.Server
+}
+
+// NewServer builds a Server bound to addr. requestShutdown is called (once, from the request goroutine) for every
+// POST /logout request to signal that a shutdown/logout should happen; it must not block on the shutdown actually
+// completing, since the response is written right after it returns, and it must be safe to call concurrently with
+// the same signal coming from elsewhere (e.g. a SIGTERM handler). The logout/exit itself is expected to run on
+// whatever goroutine owns the session state, not inside this handler.
+func NewServer(addr string, metrics *Metrics, requestShutdown func()) *Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		if metrics.LoggedIn.Load() {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		writeMetrics(w, metrics)
+	})
+	mux.HandleFunc("/logout", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		requestShutdown()
+		w.WriteHeader(http.StatusOK)
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+	})
+
+	return &Server{httpServer: &http.Server{Addr: addr, Handler: mux}}
+}
+
+// ListenAndServe runs the status server, blocking until it is shut down or fails to start. Meant to be run in its
+// own goroutine.
+func (s *Server) ListenAndServe() error {
+	return s.httpServer.ListenAndServe()
+}
+
+func writeMetrics(w http.ResponseWriter, metrics *Metrics) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	fmt.Fprintln(w, "# HELP fortiauth_auth_attempts_total Total number of login attempts made.")
+	fmt.Fprintln(w, "# TYPE fortiauth_auth_attempts_total counter")
+	fmt.Fprintf(w, "fortiauth_auth_attempts_total %d\n", metrics.AuthAttempts.Load())
+
+	fmt.Fprintln(w, "# HELP fortiauth_keepalive_failures_total Total number of failed keepalive requests.")
+	fmt.Fprintln(w, "# TYPE fortiauth_keepalive_failures_total counter")
+	fmt.Fprintf(w, "fortiauth_keepalive_failures_total %d\n", metrics.KeepaliveFailures.Load())
+
+	fmt.Fprintln(w, "# HELP fortiauth_logged_in Whether fortiauth currently considers itself logged in.")
+	fmt.Fprintln(w, "# TYPE fortiauth_logged_in gauge")
+	fmt.Fprintf(w, "fortiauth_logged_in %d\n", boolToFloat(metrics.LoggedIn.Load()))
+
+	fmt.Fprintln(w, "# HELP fortiauth_last_keepalive_timestamp_seconds Unix timestamp of the last successful keepalive.")
+	fmt.Fprintln(w, "# TYPE fortiauth_last_keepalive_timestamp_seconds gauge")
+	fmt.Fprintf(w, "fortiauth_last_keepalive_timestamp_seconds %d\n", metrics.LastKeepaliveUnix.Load())
+}
+
+func boolToFloat(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -1,261 +1,294 @@
-// A tiny CLI tool to automate the authentication and keepalive requests for IIT Kanpur's Fortinet captive portal.
+// A tiny CLI tool to automate the authentication and keepalive requests for Fortinet (and, eventually, other
+// vendors') captive portals.
 package main
 
 import (
+	"context"
 	"fmt"
-	"io"
-	"log"
 	"net/http"
-	"net/url"
 	"os"
 	"os/signal"
-	"regexp"
 	"strings"
 	"syscall"
 	"time"
 
+	"github.com/samiksome92/fortiauth/internal/credentials"
+	"github.com/samiksome92/fortiauth/internal/logging"
+	"github.com/samiksome92/fortiauth/internal/portal"
+	_ "github.com/samiksome92/fortiauth/internal/portal/fortinet"
+	_ "github.com/samiksome92/fortiauth/internal/portal/pfsense"
+	"github.com/samiksome92/fortiauth/internal/retry"
+	"github.com/samiksome92/fortiauth/internal/sessionstore"
+	"github.com/samiksome92/fortiauth/internal/status"
 	"github.com/spf13/pflag"
-	"golang.org/x/term"
 )
 
-const maxRetries = 5
-
-// Stores the current login status along with authentication and keepalive urls.
-type state struct {
-	loggedIn     bool
-	authURL      string
-	keepaliveURL string
-}
-
-// Checks whether the user is logged in.
-//
-// If any error occurs then it is returned while state remains unchanged. Otherwise `state.loggedIn` is set to the
-// appropriate value and `nil` is returned. If user is not logged in then `state.authURL` is also set.
-func check(checkURL string, state *state) error {
-	resp, err := http.Get(checkURL)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return err
-	}
-	if len(body) == 0 {
-		return fmt.Errorf("empty response body")
-	}
-
-	re := regexp.MustCompile(`^<html><body><script language="JavaScript">window\.location="(https:\/\/gateway\.iitk\.ac\.in:\d+\/fgtauth\?[a-f\d]+)";<\/script><\/body><\/html>$`)
-	match := re.FindSubmatch(body)
-
-	// There's no good way to know if regex is unmatched because fortinet changed the template or if we actually get the
-	// requested webpage. For now, we assume that if regex does not match user is logged in. Definitely need to come up
-	// with a better strategy.
-	if match == nil {
-		state.loggedIn = true
-		return nil
-	}
-
-	state.authURL = string(match[1])
-	return nil
+// session tracks the current login status along with the active portal.Session.
+type session struct {
+	loggedIn bool
+	portal.Session
 }
 
-// Authenticates the user with the supplied username and password.
-//
-// If any error occurs it is returned and state is unchanged. Otherwise `state.loggedIn` is set to `true` and
-// `state.keepaliveURL` is set to the extracted keepalive url and `nil` is returned.
-func auth(username string, password string, state *state) error {
-	resp, err := http.Get(state.authURL)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
+// login prompts for a username and password and stores them in the OS keyring for later use by KeyringProvider.
+func login() {
+	creds, err := (credentials.PromptProvider{}).Get()
 	if err != nil {
-		return err
+		fmt.Printf("Failed to read credentials: %v. Exiting program\n", err)
+		os.Exit(1)
 	}
+	defer creds.Zero()
 
-	re := regexp.MustCompile(`<input type="hidden" name="magic" value="([a-f\d]+)">`)
-	match := re.FindSubmatch(body)
-	if match == nil {
-		return fmt.Errorf("magic value not found")
-	}
-
-	// Start a new block since we use reuse `body` and would like to defer the close operation again.
-	{
-		values := url.Values{}
-		values.Set("username", username)
-		values.Set("password", password)
-		values.Set("magic", string(match[1]))
-		resp, err = http.PostForm(state.authURL[:8+strings.Index(state.authURL[8:], "/")], values)
-		if err != nil {
-			return err
-		}
-		defer resp.Body.Close()
-
-		body, err = io.ReadAll(resp.Body)
-		if err != nil {
-			return err
-		}
-
-		// Probably should relax the regex matching a bit here and just look for keepalive url.
-		re = regexp.MustCompile(`<html><body><script language="JavaScript">window\.location="(https:\/\/gateway\.iitk\.ac\.in:\d+\/keepalive\?[a-f\d]+)";<\/script><\/body><\/html>`)
-		match = re.FindSubmatch(body)
-		if match == nil {
-			return fmt.Errorf("keepalive url not found")
-		}
-
-		state.loggedIn = true
-		state.keepaliveURL = string(match[1])
-		return nil
+	if err := credentials.StoreInKeyring(creds.Username, string(creds.Password)); err != nil {
+		fmt.Printf("Failed to store credentials in keyring: %v. Exiting program\n", err)
+		os.Exit(1)
 	}
+	fmt.Println("Credentials stored in keyring")
 }
 
-// Sends a keepalive request.
-//
-// If any error occurs it is returned, otherwise returns `nil`. Does not modify `state`.
-func keepalive(state *state) error {
-	resp, err := http.Get(state.keepaliveURL)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("response status: %v", resp.Status)
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "login" {
+		login()
+		return
 	}
 
-	return nil
-}
-
-func main() {
 	help := pflag.BoolP("help", "h", false, "Print this help")
 	username := pflag.StringP("username", "u", "", "Username")
 	password := pflag.StringP("password", "p", "", "Password")
+	credentialsSource := pflag.String("credentials-source", "flags,keyring,env,netrc,prompt", "Comma-separated order of credential sources to try (flags,keyring,env,netrc,prompt)")
 	checkURL := pflag.String("url", "http://google.com", "URL to use for checking connection")
+	portalName := pflag.String("portal", "fortinet-iitk", fmt.Sprintf("Captive portal backend to use (%v)", strings.Join(portal.Names(), ", ")))
+	authRegex := pflag.String("auth-regex", "", "Override the backend's pattern for extracting the auth page's magic value (if supported)")
+	keepaliveRegex := pflag.String("keepalive-regex", "", "Override the backend's pattern for recognizing the keepalive redirect (if supported)")
 	retryTime := pflag.Int64("retry_time", 1, "Seconds to wait before retrying operations")
 	checkTime := pflag.Int64("check_time", 10, "Seconds to wait before re-checking state")
 	keepaliveTime := pflag.Int64("keepalive_time", 60, "Seconds to wait before sending keepalive request")
+	retryMax := pflag.Int("retry-max", 5, "Maximum number of retries for a single check/auth/keepalive request")
+	retryBase := pflag.Duration("retry-base", time.Second, "Base delay for exponential backoff between retries")
+	retryCap := pflag.Duration("retry-cap", 30*time.Second, "Maximum delay between retries")
+	retryJitter := pflag.Float64("retry-jitter", 0.2, "Fraction of the computed backoff (0-1) added as random jitter")
+	logFile := pflag.String("log-file", "", "Path to a log file. If unset, logs are written to stderr")
+	logFormat := pflag.String("log-format", "text", "Log format: text or json")
+	logLevel := pflag.String("log-level", "info", "Log level: debug, info, warn or error")
+	logMaxSizeMB := pflag.Int("log-max-size-mb", 100, "Maximum size in megabytes of a log file before it gets rotated")
+	logMaxBackups := pflag.Int("log-max-backups", 3, "Maximum number of rotated log files to keep")
+	logMaxAgeDays := pflag.Int("log-max-age-days", 28, "Maximum number of days to retain rotated log files")
+	statusAddr := pflag.String("status-addr", "", "Address to serve /healthz, /metrics and /logout on (e.g. 127.0.0.1:7878). Disabled if unset")
+	stateFile := pflag.String("state-file", sessionstore.DefaultPath(), "Path to the session state file used to resume after a restart")
+	noState := pflag.Bool("no-state", false, "Disable loading/saving the session state file")
 	pflag.Parse()
 
 	if *help {
 		fmt.Println("Usage: fortiauth [options]")
+		fmt.Println("       fortiauth login")
 		fmt.Println()
 		fmt.Println("Options:")
 		pflag.PrintDefaults()
 		os.Exit(0)
 	}
 
-	if *username == "" {
-		fmt.Print("username: ")
-		_, err := fmt.Scanln(username)
-		if err != nil {
-			fmt.Println("Failed to read username. Exiting program")
-			os.Exit(1)
+	logger, err := logging.New(logging.Options{
+		Format:     *logFormat,
+		Level:      *logLevel,
+		File:       *logFile,
+		MaxSizeMB:  *logMaxSizeMB,
+		MaxBackups: *logMaxBackups,
+		MaxAgeDays: *logMaxAgeDays,
+	})
+	if err != nil {
+		fmt.Printf("Invalid logging options: %v. Exiting program\n", err)
+		os.Exit(1)
+	}
+
+	providers, err := credentials.Providers(credentials.ParseSources(*credentialsSource), *username, *password)
+	if err != nil {
+		fmt.Printf("Invalid --credentials-source: %v. Exiting program\n", err)
+		os.Exit(1)
+	}
+	creds, err := credentials.Resolve(providers)
+	if err != nil {
+		fmt.Printf("Failed to resolve credentials: %v. Exiting program\n", err)
+		os.Exit(1)
+	}
+	defer creds.Zero()
+
+	client := retry.NewClient(*retryMax, *retryBase, *retryCap, *retryJitter)
+	client.Logger = logger.With("component", *portalName)
+
+	backend, err := portal.New(*portalName, client)
+	if err != nil {
+		fmt.Printf("Failed to set up portal backend: %v. Exiting program\n", err)
+		os.Exit(1)
+	}
+	if overrider, ok := backend.(portal.RegexOverrider); ok {
+		if *authRegex != "" {
+			if err := overrider.SetAuthRegex(*authRegex); err != nil {
+				fmt.Printf("Invalid --auth-regex: %v. Exiting program\n", err)
+				os.Exit(1)
+			}
+		}
+		if *keepaliveRegex != "" {
+			if err := overrider.SetKeepaliveRegex(*keepaliveRegex); err != nil {
+				fmt.Printf("Invalid --keepalive-regex: %v. Exiting program\n", err)
+				os.Exit(1)
+			}
 		}
+	} else if *authRegex != "" || *keepaliveRegex != "" {
+		fmt.Printf("Portal backend %q does not support --auth-regex/--keepalive-regex. Exiting program\n", *portalName)
+		os.Exit(1)
 	}
-	if *password == "" {
-		fmt.Print("password: ")
-		data, err := term.ReadPassword(int(os.Stdin.Fd()))
-		fmt.Println()
+
+	ctx := context.Background()
+	sess := new(session)
+	metrics := new(status.Metrics)
+
+	saveState := func() {
+		if *noState {
+			return
+		}
+		f, err := sessionstore.BuildFile(sess.loggedIn, sess.KeepaliveURL, client)
 		if err != nil {
-			fmt.Println("Failed to read password. Exiting program")
-			os.Exit(1)
+			logger.Warn("failed to build session state", "component", "state", "error", err)
+			return
+		}
+		if err := sessionstore.Save(*stateFile, f); err != nil {
+			logger.Warn("failed to save session state", "component", "state", "error", err)
+		}
+	}
+
+	if !*noState {
+		if f, err := sessionstore.Load(*stateFile); err != nil {
+			if !os.IsNotExist(err) {
+				logger.Warn("failed to load session state", "component", "state", "error", err)
+			}
+		} else if f.LoggedIn && f.KeepaliveURL != "" {
+			if err := f.Restore(client); err != nil {
+				logger.Warn("failed to restore session state", "component", "state", "error", err)
+			} else {
+				client.Logger = logger.With("component", "state")
+				if err := backend.Keepalive(ctx, portal.Session{KeepaliveURL: f.KeepaliveURL}); err != nil {
+					logger.Info("restored session is no longer valid, falling back to check/login", "component", "state", "error", err)
+				} else {
+					logger.Info("resumed session from state file", "component", "state", "keepalive_url", f.KeepaliveURL)
+					sess.loggedIn = true
+					sess.Session = portal.Session{KeepaliveURL: f.KeepaliveURL}
+				}
+			}
 		}
-		*password = string(data)
 	}
 
-	state := new(state)
-	state.loggedIn = false
+	// shutdown logs out (if logged in) and exits. sess is only ever touched from the main goroutine, so shutdown
+	// must only be called from there too; concurrent triggers (SIGTERM, /logout) signal shutdownRequested instead
+	// and let the main loop pick it up and call this itself.
+	shutdown := func() {
+		if sess.loggedIn {
+			logger.Info("logging out", "component", "logout")
+			if err := backend.Logout(ctx, sess.Session); err != nil {
+				logger.Error("failed to log out, exiting program", "component", "logout", "error", err)
+				os.Exit(1)
+			}
+			logger.Info("successfully logged out, exiting program", "component", "logout")
+		}
+		creds.Zero()
+		os.Exit(0)
+	}
+
+	// shutdownRequested is signaled by the SIGTERM handler and the status server's /logout handler, both of which
+	// run on their own goroutines. It's buffered so whichever fires first doesn't block, and requestShutdown is
+	// non-blocking so /logout can write its response immediately instead of waiting for shutdown to run.
+	shutdownRequested := make(chan struct{}, 1)
+	requestShutdown := func() {
+		select {
+		case shutdownRequested <- struct{}{}:
+		default:
+		}
+	}
+
+	if *statusAddr != "" {
+		statusServer := status.NewServer(*statusAddr, metrics, requestShutdown)
+		go func() {
+			if err := statusServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Error("status server stopped", "component", "status", "error", err)
+			}
+		}()
+		logger.Info("serving status endpoint", "component", "status", "addr", *statusAddr)
+	}
 
 	sigs := make(chan os.Signal, 1)
 	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
 	go func() {
 		<-sigs
-
-		if state.loggedIn {
-			log.Println("Logging out")
-			resp, err := http.Get(strings.Replace(state.keepaliveURL, "keepalive", "logout", 1))
-			if err != nil {
-				log.Fatalf("Failed to log out: %v. Exiting program\n", err)
-			}
-			if resp.StatusCode != http.StatusOK {
-				log.Fatalf("Failed to log out: response status %v. Exiting program\n", resp.Status)
-			}
-			log.Println("Successfully logged out. Exiting program")
-			os.Exit(0)
-		}
+		requestShutdown()
 	}()
 
-	retryCount := 0
-	var err error
+	// Each portal.Backend call already retries transient failures internally via client, so a failure returned here
+	// means the retry budget for that single request was exhausted. Rather than terminating the daemon, we log and
+	// let the main loop come back around, which keeps a flaky captive-portal transition from killing the process.
+	// Login is the exception: a rejected login (e.g. bad credentials) is a definitive answer, not a transient one,
+	// so consecutive login failures back off the same way client's own retries do, rather than re-POSTing the
+	// password once a second forever.
+	needsLogin := false
+	authFailures := 0
 	for {
 		sleepTime := *retryTime
-		if !state.loggedIn && state.authURL == "" {
-			if retryCount == 0 {
-				log.Printf("Checking state against url: %v\n", *checkURL)
-			} else {
-				log.Printf("Checking state against url: %v (Retrying %v/%v)\n", *checkURL, retryCount, maxRetries)
-			}
-			err = check(*checkURL, state)
+		if !sess.loggedIn && !needsLogin {
+			client.Logger = logger.With("component", "check")
+			logger.Info("checking state", "component", "check", "check_url", *checkURL)
+			var detected portal.State
+			detected, err = backend.Detect(ctx, *checkURL)
 			if err != nil {
-				log.Printf("Failed to check state: %v\n", err)
-				retryCount++
+				logger.Error("failed to check state", "component", "check", "error", err)
+			} else if detected.LoggedIn {
+				sess.loggedIn = true
 			} else {
-				retryCount = 0
+				needsLogin = true
 			}
-
-			if retryCount > maxRetries {
-				log.Fatalln("Maximum number of retries exceeded while trying to check state. Exiting program")
-			}
-		} else if !state.loggedIn {
-			if retryCount == 0 {
-				log.Printf("Attempting to login. Authentication url: %v\n", state.authURL)
-			} else {
-				log.Printf("Attempting to login. Authentication url: %v (Retrying %v/%v)\n", state.authURL, retryCount, maxRetries)
-			}
-			err = auth(*username, *password, state)
+		} else if !sess.loggedIn {
+			client.Logger = logger.With("component", "auth")
+			logger.Info("attempting to login", "component", "auth")
+			metrics.AuthAttempts.Add(1)
+			var portalSession portal.Session
+			portalSession, err = backend.Login(ctx, creds)
 			if err != nil {
-				log.Printf("Failed to authenticate: %v\n", err)
-				retryCount++
-			} else {
-				log.Println("Successfully logged in")
-				retryCount = 0
-			}
-
-			if retryCount > maxRetries {
-				log.Fatalln("Maximum number of retries exceeded while trying to log in. Exiting program")
-			}
-		} else if state.keepaliveURL != "" {
-			if retryCount == 0 {
-				log.Printf("Sending keepalive request. Keepalive url: %v\n", state.keepaliveURL)
+				logger.Error("failed to authenticate", "component", "auth", "error", err)
+				sleepTime = int64(retry.DefaultBackoff(*retryBase, *retryCap, *retryJitter, authFailures).Seconds())
+				if sleepTime < *retryTime {
+					sleepTime = *retryTime
+				}
+				authFailures++
 			} else {
-				log.Printf("Sending keepalive request. Keepalive url: %v (Retrying %v/%v)\n", state.keepaliveURL, retryCount, maxRetries)
+				logger.Info("successfully logged in", "component", "auth")
+				sess.loggedIn = true
+				needsLogin = false
+				sess.Session = portalSession
+				authFailures = 0
+				saveState()
 			}
-			err = keepalive(state)
+		} else if sess.KeepaliveURL != "" {
+			client.Logger = logger.With("component", "keepalive")
+			logger.Info("sending keepalive request", "component", "keepalive", "keepalive_url", sess.KeepaliveURL)
+			err = backend.Keepalive(ctx, sess.Session)
 			if err != nil {
-				log.Printf("Failed to send keepalive: %v\n", err)
-				retryCount++
+				logger.Error("failed to send keepalive", "component", "keepalive", "error", err)
+				sess.KeepaliveURL = ""
+				metrics.KeepaliveFailures.Add(1)
 			} else {
-				log.Printf("Keeping alive. Sleeping for %v seconds\n", *keepaliveTime)
-				retryCount = 0
+				logger.Info("keeping alive", "component", "keepalive", "sleep_seconds", *keepaliveTime)
 				sleepTime = *keepaliveTime
-			}
-
-			if retryCount > maxRetries {
-				log.Println("Maximum number of retries exceeded while trying to keepalive. Clearing keepalive url")
-				retryCount = 0
-				state.keepaliveURL = ""
+				metrics.LastKeepaliveUnix.Store(time.Now().Unix())
+				saveState()
 			}
 		} else {
-			log.Printf("Already logged in. No keepalive url. Sleeping for %v seconds\n", *checkTime)
-			retryCount = 0
-			state.loggedIn = false
+			logger.Info("already logged in, no keepalive url", "component", "check", "sleep_seconds", *checkTime)
+			sess.loggedIn = false
+			needsLogin = false
 			sleepTime = *checkTime
 		}
 
-		time.Sleep(time.Duration(sleepTime) * time.Second)
+		metrics.LoggedIn.Store(sess.loggedIn)
+		select {
+		case <-shutdownRequested:
+			shutdown()
+		case <-time.After(time.Duration(sleepTime) * time.Second):
+		}
 	}
 }